@@ -0,0 +1,166 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by
+// license that can be found in the LICENSE file.
+
+// Package daemon provides primitives for daemonization of golang programs.
+// It depends on the target operating system and should work on Linux, OS X
+// and Windows platforms.
+package daemon
+
+import "errors"
+
+// Status strings, shared by all platform backends.
+const (
+	success = "\t\t\t\t\t[  \033[32mOK\033[0m  ]"
+	failed  = "\t\t\t\t\t[\033[31mFAILED\033[0m]"
+)
+
+// Errors returned while installing, removing, starting or stopping a service.
+var (
+	// ErrAlreadyInstalled appears if daemon is already installed
+	ErrAlreadyInstalled = errors.New("service has already been installed")
+	// ErrNotInstalled appears if daemon is not installed
+	ErrNotInstalled = errors.New("service is not installed")
+	// ErrAlreadyRunning appears if daemon is already running
+	ErrAlreadyRunning = errors.New("service is already running")
+	// ErrAlreadyStopped appears if daemon has already been stopped
+	ErrAlreadyStopped = errors.New("service has already been stopped")
+	// ErrUserServiceUnsupported appears when a user-mode service is
+	// requested on a platform/init system that cannot support it
+	ErrUserServiceUnsupported = errors.New("user-mode services are not supported by this init system")
+	// ErrRootPrivileges appears when a system-wide operation is attempted
+	// without sufficient privileges
+	ErrRootPrivileges = errors.New("you must have root user privileges. Possibly using 'sudo' command should help")
+)
+
+// Kind identifies whether a daemon is installed system-wide or for the
+// invoking user only.
+type Kind string
+
+const (
+	// SystemDaemon installs the service system-wide, typically requiring
+	// root privileges (the default, pre-existing behavior).
+	SystemDaemon Kind = "system"
+	// UserAgent installs the service for the invoking user only (e.g.
+	// "systemctl --user"), without requiring elevated privileges.
+	UserAgent Kind = "user"
+)
+
+// Daemon is the interface implemented by every platform backend.
+type Daemon interface {
+	// Install the service into the system
+	Install(args ...string) (string, error)
+	// Remove the service and all corresponding files from the system
+	Remove() (string, error)
+	// Start the service
+	Start() (string, error)
+	// Stop the service
+	Stop() (string, error)
+	// Status - check the service status
+	Status() (string, error)
+	// Restart - restart the service
+	Restart() (string, error)
+	// ExecPath - path of the executable used to install the service
+	ExecPath(serviceName string) (string, error)
+	// List enumerates the daemons previously installed through this
+	// package in the same scope (system-wide or per-user) as this Daemon.
+	// The Windows backend is an exception: the Service Control Manager has
+	// no way to mark a service as package-installed, so it returns every
+	// registered service instead.
+	List() ([]DaemonInfo, error)
+}
+
+// DaemonInfo describes a daemon previously installed through this package,
+// as reported by List.
+type DaemonInfo struct {
+	Name        string
+	Description string
+	Version     string
+	Port        string
+	ExecPath    string
+	Active      bool
+	Enabled     bool
+}
+
+// List enumerates the daemons installed system-wide on the current
+// platform (see Daemon.List for the Windows exception). To enumerate
+// per-user daemons instead, call List on the Daemon returned by
+// New(Config{Kind: UserAgent}).
+func List() ([]DaemonInfo, error) {
+	d, err := newDaemon(Config{Kind: SystemDaemon})
+	if err != nil {
+		return nil, err
+	}
+	return d.List()
+}
+
+// Config describes how a daemon should be installed and run. Name is the
+// only required field; everything else falls back to the platform backend's
+// previous hardcoded defaults when left at its zero value.
+type Config struct {
+	Name         string
+	Description  string
+	Port         string
+	Version      string
+	Dependencies []string
+	// Kind selects system-wide vs per-user installation. Defaults to
+	// SystemDaemon.
+	Kind Kind
+
+	// Arguments are passed to the executable on every start, ahead of any
+	// extra arguments given to Install.
+	Arguments        []string
+	WorkingDirectory string
+	User             string
+	Group            string
+	Env              map[string]string
+	// Restart is the init system's restart policy (systemd Restart=).
+	// Defaults to "on-abort", matching the package's original behavior.
+	Restart     string
+	RestartSec  int
+	LimitNOFILE int
+	KillMode    string
+
+	// UnitTemplate, when set, replaces the package's built-in unit-file
+	// template (systemd unit, SysV init script or launchd plist; the Windows
+	// backend does not use it). It is parsed with text/template and
+	// executed with the same fields the built-in template receives.
+	UnitTemplate string
+}
+
+// New creates a new daemon for the current platform from cfg.
+func New(cfg Config) (Daemon, error) {
+	if cfg.Restart == "" {
+		cfg.Restart = "on-abort"
+	}
+	return newDaemon(cfg)
+}
+
+// NewSimple creates a new system-wide daemon for the current platform.
+//
+// Deprecated: use New(Config) instead.
+func NewSimple(name, port, version, description string, dependencies ...string) (Daemon, error) {
+	return New(Config{
+		Name:         name,
+		Description:  description,
+		Port:         port,
+		Version:      version,
+		Dependencies: dependencies,
+		Kind:         SystemDaemon,
+	})
+}
+
+// NewUserDaemon creates a new daemon that is installed and managed for the
+// invoking user only, without requiring root privileges.
+//
+// Deprecated: use New(Config{Kind: UserAgent}) instead.
+func NewUserDaemon(name, port, version, description string, dependencies ...string) (Daemon, error) {
+	return New(Config{
+		Name:         name,
+		Description:  description,
+		Port:         port,
+		Version:      version,
+		Dependencies: dependencies,
+		Kind:         UserAgent,
+	})
+}
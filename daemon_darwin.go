@@ -0,0 +1,408 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by
+// license that can be found in the LICENSE file.
+
+// Package daemon darwin version
+package daemon
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// darwinRecord - standard record (struct) for darwin launchd version of daemon package
+type darwinRecord struct {
+	cfg Config
+}
+
+// Get the daemon properly
+func newDaemon(cfg Config) (Daemon, error) {
+	return &darwinRecord{cfg}, nil
+}
+
+// Get executable path
+func execPath() (string, error) {
+	return os.Executable()
+}
+
+// executablePath resolves the path of the installed binary, preferring the
+// copy found on $PATH (so re-installs track upgrades) and falling back to
+// the currently running executable.
+func executablePath(name string) (string, error) {
+	if path, err := exec.LookPath(name); err == nil {
+		return filepath.Abs(path)
+	}
+	return execPath()
+}
+
+// checkPrivileges reports whether the caller has the privileges required to
+// manage a system-wide LaunchDaemon.
+func checkPrivileges() (bool, error) {
+	if os.Getuid() != 0 {
+		return false, ErrRootPrivileges
+	}
+	return true, nil
+}
+
+// label is the launchd Label used to identify the service, also the base
+// name of the plist file.
+func (darwin *darwinRecord) label() string {
+	return darwin.cfg.Name
+}
+
+// Standard service path for launchd daemons/agents
+func (darwin *darwinRecord) servicePath() string {
+	if darwin.cfg.Kind == UserAgent {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = os.Getenv("HOME")
+		}
+		return filepath.Join(home, "Library", "LaunchAgents", darwin.label()+".plist")
+	}
+	return "/Library/LaunchDaemons/" + darwin.label() + ".plist"
+}
+
+// checkPrivileges is a no-op for user-mode agents: a user always has the
+// privileges required to manage their own LaunchAgents.
+func (darwin *darwinRecord) checkPrivileges() (bool, error) {
+	if darwin.cfg.Kind == UserAgent {
+		return true, nil
+	}
+	return checkPrivileges()
+}
+
+// Is a service installed
+func (darwin *darwinRecord) isInstalled() bool {
+
+	if _, err := os.Stat(darwin.servicePath()); err == nil {
+		return true
+	}
+
+	return false
+}
+
+// Check service is running
+func (darwin *darwinRecord) checkRunning() (string, bool) {
+	output, err := exec.Command("launchctl", "list", darwin.label()).Output()
+	if err == nil {
+		if matched, err := regexp.MatchString(darwin.label(), string(output)); err == nil && matched {
+			return "Service " + darwin.cfg.Name + " is running...", true
+		}
+	}
+
+	return "Service " + darwin.cfg.Name + " is stopped", false
+}
+
+// Install the service
+func (darwin *darwinRecord) Install(args ...string) (string, error) {
+	installAction := "Install " + darwin.cfg.Description + ":"
+
+	if ok, err := darwin.checkPrivileges(); !ok {
+		return installAction + failed, err
+	}
+
+	srvPath := darwin.servicePath()
+
+	if darwin.isInstalled() {
+		return installAction + failed, ErrAlreadyInstalled
+	}
+
+	if darwin.cfg.Kind == UserAgent {
+		if err := os.MkdirAll(filepath.Dir(srvPath), 0755); err != nil {
+			return installAction + failed, err
+		}
+	}
+
+	file, err := os.Create(srvPath)
+	if err != nil {
+		return installAction + failed, err
+	}
+	defer file.Close()
+
+	execPatch, err := executablePath(darwin.cfg.Name)
+	if err != nil {
+		return installAction + failed, err
+	}
+
+	templateSrc := launchdConfig
+	if darwin.cfg.UnitTemplate != "" {
+		templateSrc = darwin.cfg.UnitTemplate
+	}
+
+	templ, err := template.New("launchdConfig").Parse(templateSrc)
+	if err != nil {
+		return installAction + failed, err
+	}
+
+	path := append([]string{execPatch}, darwin.cfg.Arguments...)
+	path = append(path, args...)
+	if err := templ.Execute(
+		file,
+		&struct {
+			Name, Port, Version, WorkingDirectory, User, Group string
+			Args, Dependencies                                 []string
+			Env                                                map[string]string
+		}{
+			darwin.label(),
+			darwin.cfg.Port,
+			darwin.cfg.Version,
+			darwin.cfg.WorkingDirectory,
+			darwin.cfg.User,
+			darwin.cfg.Group,
+			path,
+			darwin.cfg.Dependencies,
+			darwin.cfg.Env,
+		},
+	); err != nil {
+		return installAction + failed, err
+	}
+
+	if err := exec.Command("launchctl", "load", srvPath).Run(); err != nil {
+		return installAction + failed, err
+	}
+
+	return installAction + success, nil
+}
+
+// Remove the service
+func (darwin *darwinRecord) Remove() (string, error) {
+	removeAction := "Removing " + darwin.cfg.Description + ":"
+
+	if ok, err := darwin.checkPrivileges(); !ok {
+		return removeAction + failed, err
+	}
+
+	if !darwin.isInstalled() {
+		return removeAction + failed, ErrNotInstalled
+	}
+
+	if err := exec.Command("launchctl", "unload", darwin.servicePath()).Run(); err != nil {
+		return removeAction + failed, err
+	}
+
+	if err := os.Remove(darwin.servicePath()); err != nil {
+		return removeAction + failed, err
+	}
+
+	return removeAction + success, nil
+}
+
+// Start the service
+func (darwin *darwinRecord) Start() (string, error) {
+	startAction := "Starting " + darwin.cfg.Description + ":"
+
+	if ok, err := darwin.checkPrivileges(); !ok {
+		return startAction + failed, err
+	}
+
+	if !darwin.isInstalled() {
+		return startAction + failed, ErrNotInstalled
+	}
+
+	if _, ok := darwin.checkRunning(); ok {
+		return startAction + failed, ErrAlreadyRunning
+	}
+
+	if err := exec.Command("launchctl", "start", darwin.label()).Run(); err != nil {
+		return startAction + failed, err
+	}
+
+	return startAction + success, nil
+}
+
+// Stop the service
+func (darwin *darwinRecord) Stop() (string, error) {
+	stopAction := "Stopping " + darwin.cfg.Description + ":"
+
+	if ok, err := darwin.checkPrivileges(); !ok {
+		return stopAction + failed, err
+	}
+
+	if !darwin.isInstalled() {
+		return stopAction + failed, ErrNotInstalled
+	}
+
+	if _, ok := darwin.checkRunning(); !ok {
+		return stopAction + failed, ErrAlreadyStopped
+	}
+
+	if err := exec.Command("launchctl", "stop", darwin.label()).Run(); err != nil {
+		return stopAction + failed, err
+	}
+
+	return stopAction + success, nil
+}
+
+// Status - Get service status
+func (darwin *darwinRecord) Status() (string, error) {
+
+	if ok, err := darwin.checkPrivileges(); !ok {
+		return "", err
+	}
+
+	if !darwin.isInstalled() {
+		return "Status could not defined", ErrNotInstalled
+	}
+
+	statusAction, _ := darwin.checkRunning()
+
+	return statusAction, nil
+}
+
+// Path - Get service path
+func (darwin *darwinRecord) ExecPath(serviceName string) (string, error) {
+
+	if ok, err := darwin.checkPrivileges(); !ok {
+		return "", err
+	}
+
+	if !darwin.isInstalled() {
+		return "", ErrNotInstalled
+	}
+
+	data, err := os.ReadFile(darwin.servicePath())
+	if err != nil {
+		return "", err
+	}
+
+	reg := regexp.MustCompile(`<string>(.*?)</string>`)
+	matches := reg.FindAllStringSubmatch(string(data), -1)
+	if len(matches) < 2 {
+		return "", ErrNotInstalled
+	}
+
+	return matches[1][1], nil
+}
+
+// Restart the service
+func (darwin *darwinRecord) Restart() (string, error) {
+	startAction := "Restarting " + darwin.cfg.Description + ":"
+
+	if ok, err := darwin.checkPrivileges(); !ok {
+		return startAction + failed, err
+	}
+
+	if !darwin.isInstalled() {
+		return startAction + failed, ErrNotInstalled
+	}
+
+	if err := exec.Command("launchctl", "stop", darwin.label()).Run(); err != nil {
+		return startAction + failed, err
+	}
+
+	if err := exec.Command("launchctl", "start", darwin.label()).Run(); err != nil {
+		return startAction + failed, err
+	}
+
+	return startAction + success, nil
+}
+
+// List enumerates services installed through this package in the same
+// scope (system-wide or per-user) as this daemon, by scanning the
+// LaunchDaemons/LaunchAgents directory and keeping only the plists that
+// carry the Port and Version keys this package's own template always
+// writes, so unrelated LaunchDaemons/LaunchAgents dropped in the same
+// directory are skipped.
+func (darwin *darwinRecord) List() ([]DaemonInfo, error) {
+	dir := "/Library/LaunchDaemons"
+	if darwin.cfg.Kind == UserAgent {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = os.Getenv("HOME")
+		}
+		dir = filepath.Join(home, "Library", "LaunchAgents")
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, "*.plist"))
+	if err != nil {
+		return nil, err
+	}
+
+	portReg := regexp.MustCompile(`<key>Port</key>\s*<string>(.*?)</string>`)
+	versionReg := regexp.MustCompile(`<key>Version</key>\s*<string>(.*?)</string>`)
+	disabledReg := regexp.MustCompile(`<key>Disabled</key>\s*<true/>`)
+	pathReg := regexp.MustCompile(`<string>(.*?)</string>`)
+
+	infos := make([]DaemonInfo, 0, len(matches))
+	for _, path := range matches {
+		name := strings.TrimSuffix(filepath.Base(path), ".plist")
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		portMatch := portReg.FindStringSubmatch(string(data))
+		versionMatch := versionReg.FindStringSubmatch(string(data))
+		if portMatch == nil || versionMatch == nil {
+			continue
+		}
+
+		info := DaemonInfo{Name: name, Port: portMatch[1], Version: versionMatch[1]}
+		if m := pathReg.FindAllStringSubmatch(string(data), -1); len(m) > 1 {
+			info.ExecPath = m[1][1]
+		}
+
+		record := darwinRecord{cfg: Config{Name: name, Kind: darwin.cfg.Kind}}
+		_, info.Active = record.checkRunning()
+		info.Enabled = !disabledReg.MatchString(string(data))
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// launchdConfig has no direct equivalent of systemd's Requires=/After=, so
+// cfg.Dependencies is mapped onto launchd's closest primitive instead:
+// KeepAlive/OtherJobEnabled, which keeps this job alive only while the
+// named jobs are loaded and enabled.
+var launchdConfig = `<?xml version='1.0' encoding='UTF-8'?>
+<!DOCTYPE plist PUBLIC "-//Apple Computer//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd" >
+<plist version='1.0'>
+  <dict>
+    <key>Label</key>
+    <string>{{.Name}}</string>
+    <key>ProgramArguments</key>
+    <array>
+      {{range .Args}}<string>{{.}}</string>
+      {{end}}
+    </array>
+    {{if .Dependencies}}<key>KeepAlive</key>
+    <dict>
+      <key>OtherJobEnabled</key>
+      <dict>
+        {{range .Dependencies}}<key>{{.}}</key>
+        <true/>
+        {{end}}
+      </dict>
+    </dict>
+    {{else}}<key>KeepAlive</key>
+    <true/>
+    {{end}}<key>RunAtLoad</key>
+    <true/>
+    <key>Disabled</key>
+    <false/>
+    {{if .WorkingDirectory}}<key>WorkingDirectory</key>
+    <string>{{.WorkingDirectory}}</string>
+    {{end}}{{if .User}}<key>UserName</key>
+    <string>{{.User}}</string>
+    {{end}}{{if .Group}}<key>GroupName</key>
+    <string>{{.Group}}</string>
+    {{end}}{{if .Env}}<key>EnvironmentVariables</key>
+    <dict>
+      {{range $k, $v := .Env}}<key>{{$k}}</key>
+      <string>{{$v}}</string>
+      {{end}}
+    </dict>
+    {{end}}<key>Port</key>
+    <string>{{.Port}}</string>
+    <key>Version</key>
+    <string>{{.Version}}</string>
+  </dict>
+</plist>
+`
@@ -7,17 +7,58 @@ package daemon
 
 import (
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 )
 
 // Get the daemon properly
-func newDaemon(name, port string, version string, description string, dependencies []string) (Daemon, error) {
+func newDaemon(cfg Config) (Daemon, error) {
+	if isSystemd() {
+		return &systemDRecord{cfg}, nil
+	}
+	if cfg.Kind == UserAgent {
+		return nil, ErrUserServiceUnsupported
+	}
+	return &systemVRecord{cfg}, nil
+}
+
+// isSystemd detects whether the host is running under systemd. Checking
+// /run/systemd/system alone misses some containers, which share the host
+// kernel but don't mount /run the same way, so it's backed up by reading
+// the init process' comm.
+func isSystemd() bool {
 	if _, err := os.Stat("/run/systemd/system"); err == nil {
-		return &systemDRecord{name, port, version, description, dependencies}, nil
+		return true
+	}
+	if data, err := os.ReadFile("/proc/1/comm"); err == nil {
+		if strings.TrimSpace(string(data)) == "systemd" {
+			return true
+		}
 	}
-	return &systemVRecord{name, port, version, description, dependencies}, nil
+	return false
 }
 
 // Get executable path
 func execPath() (string, error) {
 	return os.Readlink("/proc/self/exe")
 }
+
+// executablePath resolves the path of the installed binary, preferring the
+// copy found on $PATH (so re-installs track upgrades) and falling back to
+// the currently running executable.
+func executablePath(name string) (string, error) {
+	if path, err := exec.LookPath(name); err == nil {
+		return filepath.Abs(path)
+	}
+	return execPath()
+}
+
+// checkPrivileges reports whether the caller has the privileges required to
+// manage a system-wide service.
+func checkPrivileges() (bool, error) {
+	if os.Getuid() != 0 {
+		return false, ErrRootPrivileges
+	}
+	return true, nil
+}
@@ -5,25 +5,57 @@
 package daemon
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
-	"regexp"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"text/template"
 )
 
 // systemDRecord - standard record (struct) for linux systemD version of daemon package
 type systemDRecord struct {
-	name         string
-	port         string
-	version      string
-	description  string
-	dependencies []string
+	cfg Config
 }
 
 // Standard service path for systemD daemons
 func (linux *systemDRecord) servicePath() string {
-	return "/etc/systemd/system/" + linux.name + ".service"
+	if linux.cfg.Kind == UserAgent {
+		return filepath.Join(userSystemdDir(), linux.cfg.Name+".service")
+	}
+	return "/etc/systemd/system/" + linux.cfg.Name + ".service"
+}
+
+// userSystemdDir returns the directory systemd searches for per-user unit
+// files, honouring $XDG_CONFIG_HOME and falling back to ~/.config.
+func userSystemdDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "systemd", "user")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = os.Getenv("HOME")
+	}
+	return filepath.Join(home, ".config", "systemd", "user")
+}
+
+// systemctlArgs prepends the "--user" flag for user-mode daemons so every
+// systemctl invocation targets the right scope.
+func (linux *systemDRecord) systemctlArgs(args ...string) []string {
+	if linux.cfg.Kind == UserAgent {
+		return append([]string{"--user"}, args...)
+	}
+	return args
+}
+
+// checkPrivileges is a no-op for user-mode daemons: a user always has the
+// privileges required to manage their own systemd --user units.
+func (linux *systemDRecord) checkPrivileges() (bool, error) {
+	if linux.cfg.Kind == UserAgent {
+		return true, nil
+	}
+	return checkPrivileges()
 }
 
 // Is a service installed
@@ -36,28 +68,79 @@ func (linux *systemDRecord) isInstalled() bool {
 	return false
 }
 
+// ServiceStatus is the deterministic systemd unit state reported by
+// `systemctl show`, used in place of scraping the locale-dependent output
+// of `systemctl status`.
+type ServiceStatus struct {
+	LoadState   string // loaded, not-found, masked ...
+	ActiveState string // active, inactive, activating, deactivating, failed ...
+	SubState    string // running, dead, failed, activating, reloading ...
+	MainPID     int
+}
+
+// Running reports whether the unit is active and its main process is up.
+func (s ServiceStatus) Running() bool {
+	return s.ActiveState == "active" && s.SubState == "running"
+}
+
+// showStatus queries `systemctl show` for the properties this package
+// cares about and parses the deterministic Key=Value output.
+func (linux *systemDRecord) showStatus() (ServiceStatus, error) {
+	output, err := exec.Command("systemctl", linux.systemctlArgs(
+		"show", linux.cfg.Name+".service",
+		"--property=ActiveState,SubState,MainPID,LoadState",
+	)...).Output()
+	if err != nil {
+		return ServiceStatus{}, err
+	}
+
+	var status ServiceStatus
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "LoadState":
+			status.LoadState = value
+		case "ActiveState":
+			status.ActiveState = value
+		case "SubState":
+			status.SubState = value
+		case "MainPID":
+			status.MainPID, _ = strconv.Atoi(value)
+		}
+	}
+
+	return status, nil
+}
+
 // Check service is running
 func (linux *systemDRecord) checkRunning() (string, bool) {
-	output, err := exec.Command("systemctl", "status", linux.name+".service").Output()
-	if err == nil {
-		if matched, err := regexp.MatchString("Active: active", string(output)); err == nil && matched {
-			reg := regexp.MustCompile("Main PID: ([0-9]+)")
-			data := reg.FindStringSubmatch(string(output))
-			if len(data) > 1 {
-				return "Service " + linux.name + " (pid  " + data[1] + ") is running...", true
-			}
-			return "Service " + linux.name + " is running...", true
+	status, err := linux.showStatus()
+	if err != nil {
+		return "Service " + linux.cfg.Name + " is stopped", false
+	}
+
+	if status.LoadState == "not-found" {
+		return "Service " + linux.cfg.Name + " is not installed", false
+	}
+
+	if status.Running() {
+		if status.MainPID > 0 {
+			return fmt.Sprintf("Service %s (pid  %d) is running...", linux.cfg.Name, status.MainPID), true
 		}
+		return "Service " + linux.cfg.Name + " is running...", true
 	}
 
-	return "Service " + linux.name + " is stopped", false
+	return fmt.Sprintf("Service %s is %s (%s)", linux.cfg.Name, status.ActiveState, status.SubState), false
 }
 
 // Install the service
 func (linux *systemDRecord) Install(args ...string) (string, error) {
-	installAction := "Install " + linux.description + ":"
+	installAction := "Install " + linux.cfg.Description + ":"
 
-	if ok, err := checkPrivileges(); !ok {
+	if ok, err := linux.checkPrivileges(); !ok {
 		return installAction + failed, err
 	}
 
@@ -67,44 +150,73 @@ func (linux *systemDRecord) Install(args ...string) (string, error) {
 		return installAction + failed, ErrAlreadyInstalled
 	}
 
+	if linux.cfg.Kind == UserAgent {
+		if err := os.MkdirAll(filepath.Dir(srvPath), 0755); err != nil {
+			return installAction + failed, err
+		}
+	}
+
 	file, err := os.Create(srvPath)
 	if err != nil {
 		return installAction + failed, err
 	}
 	defer file.Close()
 
-	execPatch, err := executablePath(linux.name)
+	execPatch, err := executablePath(linux.cfg.Name)
 	if err != nil {
 		return installAction + failed, err
 	}
 
-	templ, err := template.New("systemDConfig").Parse(systemDConfig)
+	templateSrc := systemDConfig
+	if linux.cfg.UnitTemplate != "" {
+		templateSrc = linux.cfg.UnitTemplate
+	}
+
+	templ, err := template.New("systemDConfig").Parse(templateSrc)
 	if err != nil {
 		return installAction + failed, err
 	}
 
-	path := append([]string{execPatch}, args...)
+	wantedBy := "multi-user.target"
+	if linux.cfg.Kind == UserAgent {
+		wantedBy = "default.target"
+	}
+
+	path := append([]string{execPatch}, linux.cfg.Arguments...)
+	path = append(path, args...)
 	if err := templ.Execute(
 		file,
 		&struct {
-			Name, Port, Version, Description, Dependencies, Path string
+			Name, Port, Version, Description, Dependencies, Path, WantedBy string
+			WorkingDirectory, User, Group, Restart, KillMode               string
+			RestartSec, LimitNOFILE                                        int
+			Env                                                            map[string]string
 		}{
-			linux.name,
-			linux.port,
-			linux.version,
-			linux.description,
-			strings.Join(linux.dependencies, " "),
+			linux.cfg.Name,
+			linux.cfg.Port,
+			linux.cfg.Version,
+			linux.cfg.Description,
+			strings.Join(linux.cfg.Dependencies, " "),
 			strings.Join(path, " "),
+			wantedBy,
+			linux.cfg.WorkingDirectory,
+			linux.cfg.User,
+			linux.cfg.Group,
+			linux.cfg.Restart,
+			linux.cfg.KillMode,
+			linux.cfg.RestartSec,
+			linux.cfg.LimitNOFILE,
+			linux.cfg.Env,
 		},
 	); err != nil {
 		return installAction + failed, err
 	}
 
-	if err := exec.Command("systemctl", "daemon-reload").Run(); err != nil {
+	if err := exec.Command("systemctl", linux.systemctlArgs("daemon-reload")...).Run(); err != nil {
 		return installAction + failed, err
 	}
 
-	if err := exec.Command("systemctl", "enable", linux.name+".service").Run(); err != nil {
+	if err := exec.Command("systemctl", linux.systemctlArgs("enable", linux.cfg.Name+".service")...).Run(); err != nil {
 		return installAction + failed, err
 	}
 
@@ -113,9 +225,9 @@ func (linux *systemDRecord) Install(args ...string) (string, error) {
 
 // Remove the service
 func (linux *systemDRecord) Remove() (string, error) {
-	removeAction := "Removing " + linux.description + ":"
+	removeAction := "Removing " + linux.cfg.Description + ":"
 
-	if ok, err := checkPrivileges(); !ok {
+	if ok, err := linux.checkPrivileges(); !ok {
 		return removeAction + failed, err
 	}
 
@@ -123,7 +235,7 @@ func (linux *systemDRecord) Remove() (string, error) {
 		return removeAction + failed, ErrNotInstalled
 	}
 
-	if err := exec.Command("systemctl", "disable", linux.name+".service").Run(); err != nil {
+	if err := exec.Command("systemctl", linux.systemctlArgs("disable", linux.cfg.Name+".service")...).Run(); err != nil {
 		return removeAction + failed, err
 	}
 
@@ -136,9 +248,9 @@ func (linux *systemDRecord) Remove() (string, error) {
 
 // Start the service
 func (linux *systemDRecord) Start() (string, error) {
-	startAction := "Starting " + linux.description + ":"
+	startAction := "Starting " + linux.cfg.Description + ":"
 
-	if ok, err := checkPrivileges(); !ok {
+	if ok, err := linux.checkPrivileges(); !ok {
 		return startAction + failed, err
 	}
 
@@ -150,7 +262,7 @@ func (linux *systemDRecord) Start() (string, error) {
 		return startAction + failed, ErrAlreadyRunning
 	}
 
-	if err := exec.Command("systemctl", "start", linux.name+".service").Run(); err != nil {
+	if err := exec.Command("systemctl", linux.systemctlArgs("start", linux.cfg.Name+".service")...).Run(); err != nil {
 		return startAction + failed, err
 	}
 
@@ -159,9 +271,9 @@ func (linux *systemDRecord) Start() (string, error) {
 
 // Stop the service
 func (linux *systemDRecord) Stop() (string, error) {
-	stopAction := "Stopping " + linux.description + ":"
+	stopAction := "Stopping " + linux.cfg.Description + ":"
 
-	if ok, err := checkPrivileges(); !ok {
+	if ok, err := linux.checkPrivileges(); !ok {
 		return stopAction + failed, err
 	}
 
@@ -173,7 +285,7 @@ func (linux *systemDRecord) Stop() (string, error) {
 		return stopAction + failed, ErrAlreadyStopped
 	}
 
-	if err := exec.Command("systemctl", "stop", linux.name+".service").Run(); err != nil {
+	if err := exec.Command("systemctl", linux.systemctlArgs("stop", linux.cfg.Name+".service")...).Run(); err != nil {
 		return stopAction + failed, err
 	}
 
@@ -183,7 +295,7 @@ func (linux *systemDRecord) Stop() (string, error) {
 // Status - Get service status
 func (linux *systemDRecord) Status() (string, error) {
 
-	if ok, err := checkPrivileges(); !ok {
+	if ok, err := linux.checkPrivileges(); !ok {
 		return "", err
 	}
 
@@ -199,7 +311,7 @@ func (linux *systemDRecord) Status() (string, error) {
 // Path - Get service path
 func (linux *systemDRecord) ExecPath(serviceName string) (string, error) {
 
-	if ok, err := checkPrivileges(); !ok {
+	if ok, err := linux.checkPrivileges(); !ok {
 		return "", err
 	}
 
@@ -208,19 +320,80 @@ func (linux *systemDRecord) ExecPath(serviceName string) (string, error) {
 	}
 
 	if serviceName == "" {
-		serviceName = linux.name
+		serviceName = linux.cfg.Name
 	}
 	// This maybe is falt
-	output, err := exec.Command("systemctl", "execpath", serviceName+".service").Output()
+	output, err := exec.Command("systemctl", linux.systemctlArgs("execpath", serviceName+".service")...).Output()
 
 	return string(output), err
 }
 
+// List enumerates services installed through this package in the same
+// scope (system-wide or --user) as this daemon, by scanning the unit
+// directory and cross-referencing each unit's Port/Version metadata with
+// `systemctl show`/`is-enabled`. Only units carrying both the Port= and
+// Version= keys this package's own template always writes are included, so
+// unrelated system units dropped in the same directory are skipped.
+func (linux *systemDRecord) List() ([]DaemonInfo, error) {
+	dir := "/etc/systemd/system"
+	if linux.cfg.Kind == UserAgent {
+		dir = userSystemdDir()
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, "*.service"))
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]DaemonInfo, 0, len(matches))
+	for _, path := range matches {
+		name := strings.TrimSuffix(filepath.Base(path), ".service")
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		info := DaemonInfo{Name: name}
+		var hasPort, hasVersion bool
+		for _, line := range strings.Split(string(data), "\n") {
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				continue
+			}
+			switch key {
+			case "Description":
+				info.Description = value
+			case "ExecStart":
+				info.ExecPath = value
+			case "Port":
+				info.Port, hasPort = value, true
+			case "Version":
+				info.Version, hasVersion = value, true
+			}
+		}
+		if !hasPort || !hasVersion {
+			continue
+		}
+
+		record := systemDRecord{cfg: Config{Name: name, Kind: linux.cfg.Kind}}
+		if status, err := record.showStatus(); err == nil {
+			info.Active = status.Running()
+		}
+		output, err := exec.Command("systemctl", record.systemctlArgs("is-enabled", name+".service")...).Output()
+		info.Enabled = err == nil && strings.TrimSpace(string(output)) == "enabled"
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
 // Restart the service
 func (linux *systemDRecord) Restart() (string, error) {
-	startAction := "Restarting " + linux.description + ":"
+	startAction := "Restarting " + linux.cfg.Description + ":"
 
-	if ok, err := checkPrivileges(); !ok {
+	if ok, err := linux.checkPrivileges(); !ok {
 		return startAction + failed, err
 	}
 
@@ -228,7 +401,7 @@ func (linux *systemDRecord) Restart() (string, error) {
 		return startAction + failed, ErrNotInstalled
 	}
 
-	if err := exec.Command("systemctl", "restart", linux.name+".service").Run(); err != nil {
+	if err := exec.Command("systemctl", linux.systemctlArgs("restart", linux.cfg.Name+".service")...).Run(); err != nil {
 		return startAction + failed, err
 	}
 
@@ -244,10 +417,17 @@ After={{.Dependencies}}
 PIDFile=/var/run/{{.Name}}.pid
 ExecStartPre=/bin/rm -f /var/run/{{.Name}}.pid
 ExecStart={{.Path}}
-Restart=on-abort
-Port={{.Port}}
+Restart={{.Restart}}
+{{if .RestartSec}}RestartSec={{.RestartSec}}
+{{end}}{{if .WorkingDirectory}}WorkingDirectory={{.WorkingDirectory}}
+{{end}}{{if .User}}User={{.User}}
+{{end}}{{if .Group}}Group={{.Group}}
+{{end}}{{if .LimitNOFILE}}LimitNOFILE={{.LimitNOFILE}}
+{{end}}{{if .KillMode}}KillMode={{.KillMode}}
+{{end}}{{range $k, $v := .Env}}Environment={{$k}}={{$v}}
+{{end}}Port={{.Port}}
 Version={{.Version}}
 
 [Install]
-WantedBy=multi-user.target
+WantedBy={{.WantedBy}}
 `
@@ -14,16 +14,12 @@ import (
 
 // systemVRecord - standard record (struct) for linux systemV version of daemon package
 type systemVRecord struct {
-	name         string
-	port         string
-	version      string
-	description  string
-	dependencies []string
+	cfg Config
 }
 
 // Standard service path for systemV daemons
 func (linux *systemVRecord) servicePath() string {
-	return "/etc/init.d/" + linux.name
+	return "/etc/init.d/" + linux.cfg.Name
 }
 
 // Is a service installed
@@ -38,26 +34,26 @@ func (linux *systemVRecord) isInstalled() bool {
 
 // Check service is running
 func (linux *systemVRecord) checkRunning() (string, bool) {
-	output, err := exec.Command("service", linux.name, "status").Output()
+	output, err := exec.Command("service", linux.cfg.Name, "status").Output()
 	if err == nil {
 		if matched, err := regexp.MatchString("running", string(output)); err == nil && matched {
-			if matched, err := regexp.MatchString(linux.name, string(output)); err == nil && matched {
+			if matched, err := regexp.MatchString(linux.cfg.Name, string(output)); err == nil && matched {
 				reg := regexp.MustCompile("pid  ([0-9]+)")
 				data := reg.FindStringSubmatch(string(output))
 				if len(data) > 1 {
-					return "Service " + linux.name + " (pid  " + data[1] + ") is running...", true
+					return "Service " + linux.cfg.Name + " (pid  " + data[1] + ") is running...", true
 				}
-				return "Service " + linux.name + " is running...", true
+				return "Service " + linux.cfg.Name + " is running...", true
 			}
 		}
 	}
 
-	return "Service " + linux.name + " is stopped", false
+	return "Service " + linux.cfg.Name + " is stopped", false
 }
 
 // Install the service
 func (linux *systemVRecord) Install(args ...string) (string, error) {
-	installAction := "Install " + linux.description + ":"
+	installAction := "Install " + linux.cfg.Description + ":"
 
 	if ok, err := checkPrivileges(); !ok {
 		return installAction + failed, err
@@ -75,21 +71,46 @@ func (linux *systemVRecord) Install(args ...string) (string, error) {
 	}
 	defer file.Close()
 
-	execPatch, err := executablePath(linux.name)
+	execPatch, err := executablePath(linux.cfg.Name)
 	if err != nil {
 		return installAction + failed, err
 	}
 
-	templ, err := template.New("systemVConfig").Parse(systemVConfig)
+	templateSrc := systemVConfig
+	if linux.cfg.UnitTemplate != "" {
+		templateSrc = linux.cfg.UnitTemplate
+	}
+
+	templ, err := template.New("systemVConfig").Parse(templateSrc)
 	if err != nil {
 		return installAction + failed, err
 	}
 
+	serviceArgs := append(append([]string{}, linux.cfg.Arguments...), args...)
 	if err := templ.Execute(
 		file,
 		&struct {
-			Name, Port, Version, Description, Path, Args string
-		}{linux.name, linux.port, linux.version, linux.description, execPatch, strings.Join(args, " ")},
+			Name, Port, Version, Description, Dependencies, Path, Args string
+			WorkingDirectory, User, Group, Restart, KillMode           string
+			RestartSec, LimitNOFILE                                    int
+			Env                                                        map[string]string
+		}{
+			linux.cfg.Name,
+			linux.cfg.Port,
+			linux.cfg.Version,
+			linux.cfg.Description,
+			strings.Join(linux.cfg.Dependencies, " "),
+			execPatch,
+			strings.Join(serviceArgs, " "),
+			linux.cfg.WorkingDirectory,
+			linux.cfg.User,
+			linux.cfg.Group,
+			linux.cfg.Restart,
+			linux.cfg.KillMode,
+			linux.cfg.RestartSec,
+			linux.cfg.LimitNOFILE,
+			linux.cfg.Env,
+		},
 	); err != nil {
 		return installAction + failed, err
 	}
@@ -99,12 +120,12 @@ func (linux *systemVRecord) Install(args ...string) (string, error) {
 	}
 
 	for _, i := range [...]string{"2", "3", "4", "5"} {
-		if err := os.Symlink(srvPath, "/etc/rc"+i+".d/S87"+linux.name); err != nil {
+		if err := os.Symlink(srvPath, "/etc/rc"+i+".d/S87"+linux.cfg.Name); err != nil {
 			continue
 		}
 	}
 	for _, i := range [...]string{"0", "1", "6"} {
-		if err := os.Symlink(srvPath, "/etc/rc"+i+".d/K17"+linux.name); err != nil {
+		if err := os.Symlink(srvPath, "/etc/rc"+i+".d/K17"+linux.cfg.Name); err != nil {
 			continue
 		}
 	}
@@ -114,7 +135,7 @@ func (linux *systemVRecord) Install(args ...string) (string, error) {
 
 // Remove the service
 func (linux *systemVRecord) Remove() (string, error) {
-	removeAction := "Removing " + linux.description + ":"
+	removeAction := "Removing " + linux.cfg.Description + ":"
 
 	if ok, err := checkPrivileges(); !ok {
 		return removeAction + failed, err
@@ -129,12 +150,12 @@ func (linux *systemVRecord) Remove() (string, error) {
 	}
 
 	for _, i := range [...]string{"2", "3", "4", "5"} {
-		if err := os.Remove("/etc/rc" + i + ".d/S87" + linux.name); err != nil {
+		if err := os.Remove("/etc/rc" + i + ".d/S87" + linux.cfg.Name); err != nil {
 			continue
 		}
 	}
 	for _, i := range [...]string{"0", "1", "6"} {
-		if err := os.Remove("/etc/rc" + i + ".d/K17" + linux.name); err != nil {
+		if err := os.Remove("/etc/rc" + i + ".d/K17" + linux.cfg.Name); err != nil {
 			continue
 		}
 	}
@@ -144,7 +165,7 @@ func (linux *systemVRecord) Remove() (string, error) {
 
 // Start the service
 func (linux *systemVRecord) Start() (string, error) {
-	startAction := "Starting " + linux.description + ":"
+	startAction := "Starting " + linux.cfg.Description + ":"
 
 	if ok, err := checkPrivileges(); !ok {
 		return startAction + failed, err
@@ -158,7 +179,7 @@ func (linux *systemVRecord) Start() (string, error) {
 		return startAction + failed, ErrAlreadyRunning
 	}
 
-	if err := exec.Command("service", linux.name, "start").Run(); err != nil {
+	if err := exec.Command("service", linux.cfg.Name, "start").Run(); err != nil {
 		return startAction + failed, err
 	}
 
@@ -167,7 +188,7 @@ func (linux *systemVRecord) Start() (string, error) {
 
 // Stop the service
 func (linux *systemVRecord) Stop() (string, error) {
-	stopAction := "Stopping " + linux.description + ":"
+	stopAction := "Stopping " + linux.cfg.Description + ":"
 
 	if ok, err := checkPrivileges(); !ok {
 		return stopAction + failed, err
@@ -181,7 +202,7 @@ func (linux *systemVRecord) Stop() (string, error) {
 		return stopAction + failed, ErrAlreadyStopped
 	}
 
-	if err := exec.Command("service", linux.name, "stop").Run(); err != nil {
+	if err := exec.Command("service", linux.cfg.Name, "stop").Run(); err != nil {
 		return stopAction + failed, err
 	}
 
@@ -216,7 +237,7 @@ func (linux *systemVRecord) ExecPath(serviceName string) (string, error) {
 	}
 
 	if serviceName == "" {
-		serviceName = linux.name
+		serviceName = linux.cfg.Name
 	}
 	output, err := exec.Command("service", serviceName, "execpath").Output()
 
@@ -225,7 +246,7 @@ func (linux *systemVRecord) ExecPath(serviceName string) (string, error) {
 
 // Restart the service
 func (linux *systemVRecord) Restart() (string, error) {
-	startAction := "Restarting " + linux.description + ":"
+	startAction := "Restarting " + linux.cfg.Description + ":"
 
 	if ok, err := checkPrivileges(); !ok {
 		return startAction + failed, err
@@ -235,13 +256,72 @@ func (linux *systemVRecord) Restart() (string, error) {
 		return startAction + failed, ErrNotInstalled
 	}
 
-	if err := exec.Command("service", linux.name, "restart").Run(); err != nil {
+	if err := exec.Command("service", linux.cfg.Name, "restart").Run(); err != nil {
 		return startAction + failed, err
 	}
 
 	return startAction + success, nil
 }
 
+// List enumerates services installed through this package by walking
+// /etc/init.d and including only the scripts whose /etc/rc2.d start symlink
+// (created by Install) still points back at them. That symlink is the
+// marker distinguishing this package's scripts from unrelated system init
+// scripts, so List never runs description/version/execpath against a
+// script this package didn't create.
+func (linux *systemVRecord) List() ([]DaemonInfo, error) {
+	entries, err := os.ReadDir("/etc/init.d")
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]DaemonInfo, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+
+		enabled, managed := managedBySymlink(name)
+		if !managed {
+			continue
+		}
+
+		description, err := exec.Command("service", name, "description").Output()
+		if err != nil {
+			continue
+		}
+		version, _ := exec.Command("service", name, "version").Output()
+		execPath, _ := exec.Command("service", name, "execpath").Output()
+
+		record := systemVRecord{cfg: Config{Name: name}}
+		_, active := record.checkRunning()
+
+		infos = append(infos, DaemonInfo{
+			Name:        name,
+			Description: strings.TrimSpace(string(description)),
+			Version:     strings.TrimSpace(string(version)),
+			ExecPath:    strings.TrimSpace(string(execPath)),
+			Active:      active,
+			Enabled:     enabled,
+		})
+	}
+
+	return infos, nil
+}
+
+// managedBySymlink reports whether /etc/rc2.d's start-runlevel symlink for
+// name still points back at /etc/init.d/name, the same symlink Install
+// creates and Remove deletes. Its presence doubles as both the "this
+// package installed it" marker and the enabled flag.
+func managedBySymlink(name string) (enabled, managed bool) {
+	target, err := os.Readlink("/etc/rc2.d/S87" + name)
+	if err != nil {
+		return false, false
+	}
+	return true, target == "/etc/init.d/"+name
+}
+
 var systemVConfig = `#! /bin/sh
 #
 #       /etc/rc.d/init.d/{{.Name}}
@@ -252,7 +332,7 @@ var systemVConfig = `#! /bin/sh
 # description: Starts and stops a single {{.Name}} instance on this system
 
 ### BEGIN INIT INFO
-# Provides: {{.Name}} 
+# Provides: {{.Name}}
 # Required-Start: $network $named
 # Required-Stop: $network $named
 # Default-Start: 2 3 4 5
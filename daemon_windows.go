@@ -0,0 +1,352 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by
+// license that can be found in the LICENSE file.
+
+// Package daemon windows version
+package daemon
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// windowsRecord - standard record (struct) for windows SCM version of daemon package
+type windowsRecord struct {
+	cfg Config
+}
+
+// Get the daemon properly
+func newDaemon(cfg Config) (Daemon, error) {
+	if cfg.Kind == UserAgent {
+		return nil, ErrUserServiceUnsupported
+	}
+	return &windowsRecord{cfg}, nil
+}
+
+// Get executable path
+func execPath() (string, error) {
+	return os.Executable()
+}
+
+// executablePath resolves the path of the installed binary
+func executablePath(name string) (string, error) {
+	return execPath()
+}
+
+// checkPrivileges reports whether the caller has the privileges required to
+// manage services through the Service Control Manager.
+func checkPrivileges() (bool, error) {
+	m, err := mgr.Connect()
+	if err != nil {
+		return false, ErrRootPrivileges
+	}
+	defer m.Disconnect()
+
+	return true, nil
+}
+
+// Is a service installed
+func (windows *windowsRecord) isInstalled() bool {
+	m, err := mgr.Connect()
+	if err != nil {
+		return false
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(windows.cfg.Name)
+	if err != nil {
+		return false
+	}
+	s.Close()
+
+	return true
+}
+
+// Install the service
+func (windows *windowsRecord) Install(args ...string) (string, error) {
+	installAction := "Install " + windows.cfg.Description + ":"
+
+	if ok, err := checkPrivileges(); !ok {
+		return installAction + failed, err
+	}
+
+	execPatch, err := executablePath(windows.cfg.Name)
+	if err != nil {
+		return installAction + failed, err
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return installAction + failed, err
+	}
+	defer m.Disconnect()
+
+	if windows.isInstalled() {
+		return installAction + failed, ErrAlreadyInstalled
+	}
+
+	serviceArgs := append(append([]string{}, windows.cfg.Arguments...), args...)
+	s, err := m.CreateService(windows.cfg.Name, execPatch, mgr.Config{
+		DisplayName:  windows.cfg.Description,
+		Description:  windows.cfg.Description,
+		StartType:    mgr.StartAutomatic,
+		Dependencies: windows.cfg.Dependencies,
+	}, serviceArgs...)
+	if err != nil {
+		return installAction + failed, err
+	}
+	defer s.Close()
+
+	return installAction + success, nil
+}
+
+// Remove the service
+func (windows *windowsRecord) Remove() (string, error) {
+	removeAction := "Removing " + windows.cfg.Description + ":"
+
+	if ok, err := checkPrivileges(); !ok {
+		return removeAction + failed, err
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return removeAction + failed, err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(windows.cfg.Name)
+	if err != nil {
+		return removeAction + failed, ErrNotInstalled
+	}
+	defer s.Close()
+
+	if err := s.Delete(); err != nil {
+		return removeAction + failed, err
+	}
+
+	return removeAction + success, nil
+}
+
+// Start the service
+func (windows *windowsRecord) Start() (string, error) {
+	startAction := "Starting " + windows.cfg.Description + ":"
+
+	if ok, err := checkPrivileges(); !ok {
+		return startAction + failed, err
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return startAction + failed, err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(windows.cfg.Name)
+	if err != nil {
+		return startAction + failed, ErrNotInstalled
+	}
+	defer s.Close()
+
+	if err := s.Start(); err != nil {
+		return startAction + failed, err
+	}
+
+	return startAction + success, nil
+}
+
+// Stop the service
+func (windows *windowsRecord) Stop() (string, error) {
+	stopAction := "Stopping " + windows.cfg.Description + ":"
+
+	if ok, err := checkPrivileges(); !ok {
+		return stopAction + failed, err
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return stopAction + failed, err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(windows.cfg.Name)
+	if err != nil {
+		return stopAction + failed, ErrNotInstalled
+	}
+	defer s.Close()
+
+	if _, err := s.Control(svc.Stop); err != nil {
+		return stopAction + failed, err
+	}
+
+	return stopAction + success, nil
+}
+
+// Status - Get service status
+func (windows *windowsRecord) Status() (string, error) {
+
+	if ok, err := checkPrivileges(); !ok {
+		return "", err
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return "", err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(windows.cfg.Name)
+	if err != nil {
+		return "Status could not defined", ErrNotInstalled
+	}
+	defer s.Close()
+
+	status, err := s.Query()
+	if err != nil {
+		return "", err
+	}
+
+	switch status.State {
+	case svc.Running:
+		return "Service " + windows.cfg.Name + " is running...", nil
+	case svc.Stopped:
+		return "Service " + windows.cfg.Name + " is stopped", nil
+	default:
+		return fmt.Sprintf("Service %s is in state %d", windows.cfg.Name, status.State), nil
+	}
+}
+
+// Path - Get service path
+func (windows *windowsRecord) ExecPath(serviceName string) (string, error) {
+
+	if ok, err := checkPrivileges(); !ok {
+		return "", err
+	}
+
+	if serviceName == "" {
+		serviceName = windows.cfg.Name
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return "", err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		return "", ErrNotInstalled
+	}
+	defer s.Close()
+
+	svcCfg, err := s.Config()
+	if err != nil {
+		return "", err
+	}
+
+	return svcCfg.BinaryPathName, nil
+}
+
+// List enumerates services registered with the Service Control Manager.
+// Unlike the other platforms, the SCM has no concept of "installed through
+// this package", so List returns every registered service rather than only
+// the ones this package created.
+func (windows *windowsRecord) List() ([]DaemonInfo, error) {
+	m, err := mgr.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.Disconnect()
+
+	names, err := m.ListServices()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]DaemonInfo, 0, len(names))
+	for _, name := range names {
+		s, err := m.OpenService(name)
+		if err != nil {
+			continue
+		}
+
+		info := DaemonInfo{Name: name}
+		if svcCfg, err := s.Config(); err == nil {
+			info.Description = svcCfg.DisplayName
+			info.ExecPath = svcCfg.BinaryPathName
+			info.Enabled = svcCfg.StartType != mgr.StartDisabled
+		}
+		if status, err := s.Query(); err == nil {
+			info.Active = status.State == svc.Running
+		}
+
+		s.Close()
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// Restart the service
+func (windows *windowsRecord) Restart() (string, error) {
+	startAction := "Restarting " + windows.cfg.Description + ":"
+
+	if _, err := windows.Stop(); err != nil {
+		return startAction + failed, err
+	}
+
+	if _, err := windows.Start(); err != nil {
+		return startAction + failed, err
+	}
+
+	return startAction + success, nil
+}
+
+// windowsHandler adapts a long-running daemon function to the Windows
+// Service Control Manager by implementing svc.Handler.
+type windowsHandler struct {
+	run func(stop <-chan struct{})
+}
+
+// Execute implements svc.Handler: it runs the handler's work function until
+// the Service Control Manager requests a stop or shutdown.
+func (h *windowsHandler) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	changes <- svc.Status{State: svc.StartPending}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		h.run(stop)
+		close(done)
+	}()
+
+	changes <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case <-done:
+			changes <- svc.Status{State: svc.StopPending}
+			return false, 0
+		case c := <-r:
+			switch c.Cmd {
+			case svc.Interrogate:
+				changes <- c.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				changes <- svc.Status{State: svc.StopPending}
+				close(stop)
+				<-done
+				return false, 0
+			}
+		}
+	}
+}
+
+// RunService blocks, running name as a Windows service and invoking run
+// until the Service Control Manager requests a stop. It must be called
+// from the service's own process (typically main), not from a client that
+// only wants to control an already-registered service.
+func RunService(name string, run func(stop <-chan struct{})) error {
+	return svc.Run(name, &windowsHandler{run: run})
+}
@@ -0,0 +1,202 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by
+// license that can be found in the LICENSE file.
+
+//go:build !windows
+
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// maxLogSize is the size past which Run rotates a log file on startup,
+// rather than letting it grow without bound.
+const maxLogSize = 10 * 1024 * 1024 // 10 MiB
+
+// Run gives an installed daemon a full lifecycle so it doesn't have to
+// reimplement signal handling, log redirection, a PID file and systemd
+// readiness notifications itself: it installs a signal handler for
+// SIGINT/SIGTERM/SIGHUP that cancels ctx, redirects stdout/stderr to
+// /var/log/<name>.{log,err} (the same paths systemVConfig already writes
+// into stdoutlog/stderrlog), writes /var/run/<name>.pid, and, when running
+// under systemd, notifies readiness (and services the watchdog when
+// WATCHDOG_USEC is set) over NOTIFY_SOCKET. It then calls work and blocks
+// until work returns; a termination signal cancels work's ctx, and Run still
+// waits for work to return before it returns work's error.
+//
+// Run is unix-only: /var/log and /var/run aren't meaningful paths on
+// Windows, and Windows services are already driven by the Service Control
+// Manager. Windows daemons should call RunService instead.
+func Run(ctx context.Context, cfg Config, work func(ctx context.Context) error) error {
+	if cfg.Name == "" {
+		return errors.New("daemon: Config.Name is required")
+	}
+
+	closer, err := redirectOutput("/var/log", cfg.Name)
+	if err != nil {
+		return fmt.Errorf("daemon: redirecting output: %w", err)
+	}
+	defer closer.Close()
+
+	pidPath := filepath.Join("/var/run", cfg.Name+".pid")
+	if err := writePIDFile(pidPath); err != nil {
+		return fmt.Errorf("daemon: writing pid file: %w", err)
+	}
+	defer os.Remove(pidPath)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, terminationSignals()...)
+	defer signal.Stop(sig)
+
+	notifyReady()
+	stopWatchdog := startWatchdog(ctx)
+	defer stopWatchdog()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- work(ctx)
+	}()
+
+	select {
+	case <-sig:
+		notifyStopping()
+		cancel()
+		return <-errCh
+	case err := <-errCh:
+		notifyStopping()
+		return err
+	}
+}
+
+// writePIDFile records the current process' PID, mirroring the PIDFile the
+// systemd/SysV unit templates already expect at this path.
+func writePIDFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())), 0644)
+}
+
+type multiCloser []io.Closer
+
+func (m multiCloser) Close() error {
+	var first error
+	for _, c := range m {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// redirectOutput rotates oversized logs from a previous run, then points
+// both the os.Stdout/os.Stderr variables and file descriptors 1/2 at fresh
+// log files so output from this package, the standard library and the
+// Go runtime all land in the same place.
+func redirectOutput(logDir, name string) (io.Closer, error) {
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return nil, err
+	}
+
+	outPath := filepath.Join(logDir, name+".log")
+	errPath := filepath.Join(logDir, name+".err")
+
+	rotateIfLarge(outPath)
+	rotateIfLarge(errPath)
+
+	outFile, err := os.OpenFile(outPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	errFile, err := os.OpenFile(errPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		outFile.Close()
+		return nil, err
+	}
+
+	os.Stdout = outFile
+	os.Stderr = errFile
+	redirectFds(outFile, errFile)
+
+	return multiCloser{outFile, errFile}, nil
+}
+
+// rotateIfLarge archives path if it has grown past maxLogSize, so a
+// long-lived daemon doesn't fill the disk with a single unbounded file.
+func rotateIfLarge(path string) {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() < maxLogSize {
+		return
+	}
+	os.Rename(path, path+"."+time.Now().Format("20060102T150405"))
+}
+
+// sdNotify sends state to the systemd notification socket named by
+// $NOTIFY_SOCKET, if any. It is a no-op outside of systemd.
+func sdNotify(state string) error {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return nil
+	}
+	if addr[0] == '@' {
+		addr = "\x00" + addr[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+func notifyReady() {
+	_ = sdNotify("READY=1")
+}
+
+func notifyStopping() {
+	_ = sdNotify("STOPPING=1")
+}
+
+// startWatchdog, when systemd asked for watchdog pings via WATCHDOG_USEC,
+// pings it at half that interval until ctx is done or the returned stop
+// function is called.
+func startWatchdog(ctx context.Context) (stop func()) {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Duration(usec) * time.Microsecond / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				_ = sdNotify("WATCHDOG=1")
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
@@ -0,0 +1,30 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by
+// license that can be found in the LICENSE file.
+
+//go:build !windows
+
+package daemon
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// terminationSignals are the signals Run treats as a request to shut down.
+func terminationSignals() []os.Signal {
+	return []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP}
+}
+
+// redirectFds duplicates out/err onto file descriptors 1 and 2 so output
+// written directly to the process' stdout/stderr (panics, cgo, exec'd
+// children) lands in the log files too, not just writes through the
+// os.Stdout/os.Stderr variables. unix.Dup2 is used instead of syscall.Dup2,
+// which is unavailable on several modern unix syscall ABIs (e.g.
+// linux/arm64, linux/riscv64).
+func redirectFds(out, errFile *os.File) {
+	unix.Dup2(int(out.Fd()), 1)
+	unix.Dup2(int(errFile.Fd()), 2)
+}